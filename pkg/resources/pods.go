@@ -0,0 +1,322 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+// PodAccessor is a convenience type for listing pods and extracting useful
+// aggregations (addresses, counts, ...) scoped to a single Revision.
+type PodAccessor struct {
+	podsLister  corev1listers.PodNamespaceLister
+	namespace   string
+	selector    labels.Selector
+	topology    *PodTopologyIndex
+	podInformer coreinformers.PodInformer
+}
+
+// NewPodAccessor creates a new instance of PodAccessor for the given Revision.
+func NewPodAccessor(lister corev1listers.PodLister, namespace, revisionName string, opts ...PodAccessorOption) PodAccessor {
+	pa := PodAccessor{
+		podsLister: lister.Pods(namespace),
+		namespace:  namespace,
+		selector:   labels.SelectorFromSet(labels.Set{serving.RevisionLabelKey: revisionName}),
+	}
+	for _, opt := range opts {
+		opt(&pa)
+	}
+	return pa
+}
+
+// PodIPsByAge returns the IP addresses of all pods for this Revision which have an IP,
+// ordered by age, oldest first.
+func (pa PodAccessor) PodIPsByAge() ([]string, error) {
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		ti, tj := pods[i].Status.StartTime, pods[j].Status.StartTime
+		if ti == nil || tj == nil {
+			return tj == nil && ti != nil
+		}
+		return ti.Before(tj)
+	})
+
+	ips := make([]string, 0, len(pods))
+	for _, p := range pods {
+		if ip := p.Status.PodIP; ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// PodIPsByActive returns the IP addresses of all pods for this Revision which have an
+// IP, ordered using a kubectl-style "active pods" comparator: assigned pods before
+// unassigned ones, Running before Pending before everything else, Ready before
+// not-Ready, fewer restarts before more, and, as a final tie-breaker, the pod whose
+// Ready condition transitioned most recently wins. This lets callers that probe or
+// route to a subset of pods prefer the ones most likely to serve traffic well instead
+// of just the oldest ones.
+func (pa PodAccessor) PodIPsByActive() ([]string, error) {
+	pods, err := pa.activePods()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(pods))
+	for _, p := range pods {
+		if ip := p.Status.PodIP; ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// ReadyPodIPsByActive returns the IP addresses of only the currently Ready pods for
+// this Revision, in the same "active pods" order as PodIPsByActive. The throttler
+// uses this to route requests exclusively to pods that are actually able to serve
+// them right now.
+func (pa PodAccessor) ReadyPodIPsByActive() ([]string, error) {
+	pods, err := pa.activePods()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(pods))
+	for _, p := range pods {
+		if ip := p.Status.PodIP; ip != "" && podReady(p) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// activePods lists the pods for this Revision sorted most-useful-first, per
+// podMoreActive.
+func (pa PodAccessor) activePods() ([]*corev1.Pod, error) {
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return podMoreActive(pods[i], pods[j])
+	})
+	return pods, nil
+}
+
+// podMoreActive reports whether pod a should be preferred over pod b when probing
+// or routing, mirroring (inverted) the ordering kubectl uses to pick which pods are
+// safest to delete first.
+func podMoreActive(a, b *corev1.Pod) bool {
+	// Unassigned pods (no Node yet) sort last.
+	if assignedA, assignedB := a.Spec.NodeName != "", b.Spec.NodeName != ""; assignedA != assignedB {
+		return assignedA
+	}
+
+	// Running before Pending before everything else (Unknown, Succeeded, Failed).
+	if ra, rb := podPhaseRank(a.Status.Phase), podPhaseRank(b.Status.Phase); ra != rb {
+		return ra < rb
+	}
+
+	// Ready before not-Ready.
+	if readyA, readyB := podReady(a), podReady(b); readyA != readyB {
+		return readyA
+	}
+
+	// Fewer restarts before more restarts.
+	if ra, rb := maxRestartCount(a), maxRestartCount(b); ra != rb {
+		return ra < rb
+	}
+
+	// Most recently Ready wins ties.
+	return podReadyTransitionTime(a).After(podReadyTransitionTime(b).Time)
+}
+
+// podPhaseRank orders PodRunning ahead of PodPending ahead of every other phase.
+func podPhaseRank(phase corev1.PodPhase) int {
+	switch phase {
+	case corev1.PodRunning:
+		return 0
+	case corev1.PodPending:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// podReady reports whether the pod's PodReady condition is currently True.
+func podReady(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podReadyTransitionTime returns the LastTransitionTime of the pod's PodReady
+// condition, or the zero time if the pod has no such condition yet.
+func podReadyTransitionTime(p *corev1.Pod) metav1.Time {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.LastTransitionTime
+		}
+	}
+	return metav1.Time{}
+}
+
+// maxRestartCount returns the highest RestartCount across the pod's containers.
+func maxRestartCount(p *corev1.Pod) int32 {
+	var max int32
+	for _, cs := range p.Status.ContainerStatuses {
+		if cs.RestartCount > max {
+			max = cs.RestartCount
+		}
+	}
+	return max
+}
+
+// PendingTerminatingCount returns the number of pods that are currently in Pending
+// phase and the number of pods that are currently terminating (have a
+// DeletionTimestamp set) for this Revision.
+func (pa PodAccessor) PendingTerminatingCount() (int, int, error) {
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var pending, terminating int
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil {
+			terminating++
+		} else if p.Status.Phase == corev1.PodPending {
+			pending++
+		}
+	}
+	return pending, terminating, nil
+}
+
+// PodStates is a breakdown of the pods for a Revision into the more fine-grained
+// states used by tools such as kube-state-metrics, so that failure signals like a
+// stuck image pull aren't hidden behind a generic "pending" bucket. A pod can
+// contribute to more than one counter, e.g. a Running pod is also Ready once its
+// readiness probe passes.
+//
+// This type only exposes the computed breakdown; it does not itself short-circuit
+// autoscaler scale-up decisions or surface into Revision status. Wiring a decider
+// or status reconciler up to read PodStates (and act on persistent
+// ImagePullBackOff/CrashLoopBackOff) is tracked as follow-up work against whatever
+// package owns those paths.
+type PodStates struct {
+	Running              int
+	Ready                int
+	PendingScheduled     int
+	PendingUnschedulable int
+	ContainerCreating    int
+	ImagePullBackOff     int
+	CrashLoopBackOff     int
+	OOMKilled            int
+	Terminating          int
+	Failed               int
+}
+
+// PodStates returns the PodStates breakdown for this Revision.
+func (pa PodAccessor) PodStates() (PodStates, error) {
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return PodStates{}, err
+	}
+
+	var s PodStates
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil {
+			s.Terminating++
+			continue
+		}
+
+		switch p.Status.Phase {
+		case corev1.PodRunning:
+			s.Running++
+		case corev1.PodFailed:
+			s.Failed++
+		case corev1.PodPending:
+			if podUnschedulable(p) {
+				s.PendingUnschedulable++
+			} else {
+				s.PendingScheduled++
+			}
+		}
+
+		if podReady(p) {
+			s.Ready++
+		}
+
+		var creating, imagePullBackOff, crashLoopBackOff, oomKilled bool
+		for _, cs := range p.Status.ContainerStatuses {
+			if w := cs.State.Waiting; w != nil {
+				switch w.Reason {
+				case "ContainerCreating":
+					creating = true
+				case "ImagePullBackOff", "ErrImagePull":
+					imagePullBackOff = true
+				case "CrashLoopBackOff":
+					crashLoopBackOff = true
+				}
+			}
+			if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" {
+				oomKilled = true
+			}
+		}
+		if creating {
+			s.ContainerCreating++
+		}
+		if imagePullBackOff {
+			s.ImagePullBackOff++
+		}
+		if crashLoopBackOff {
+			s.CrashLoopBackOff++
+		}
+		if oomKilled {
+			s.OOMKilled++
+		}
+	}
+	return s, nil
+}
+
+// podUnschedulable reports whether the pod's PodScheduled condition is currently
+// False, i.e. the scheduler has looked at it and couldn't place it.
+func podUnschedulable(p *corev1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodScheduled {
+			return c.Status == corev1.ConditionFalse
+		}
+	}
+	return false
+}
@@ -96,6 +96,188 @@ func TestPodsSortedByAge(t *testing.T) {
 	}
 }
 
+func TestPodIPsByActive(t *testing.T) {
+	aTime := time.Now()
+
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		want []string
+	}{{
+		name: "no pods",
+	}, {
+		name: "ready before not-ready",
+		pods: []*corev1.Pod{
+			pod("not-ready", withNode("n1"), withPhase(corev1.PodRunning), notReady, withIP("1.1.1.1")),
+			pod("ready", withNode("n1"), withPhase(corev1.PodRunning), readyAt(aTime), withIP("2.2.2.2")),
+		},
+		want: []string{"2.2.2.2", "1.1.1.1"},
+	}, {
+		name: "running before pending",
+		pods: []*corev1.Pod{
+			pod("pending", withNode("n1"), withPhase(corev1.PodPending), withIP("1.1.1.1")),
+			pod("running", withNode("n1"), withPhase(corev1.PodRunning), withIP("2.2.2.2")),
+		},
+		want: []string{"2.2.2.2", "1.1.1.1"},
+	}, {
+		name: "assigned before unassigned",
+		pods: []*corev1.Pod{
+			pod("unassigned", withIP("1.1.1.1")),
+			pod("assigned", withNode("n1"), withIP("2.2.2.2")),
+		},
+		want: []string{"2.2.2.2", "1.1.1.1"},
+	}, {
+		name: "fewer restarts before more",
+		pods: []*corev1.Pod{
+			pod("flappy", withNode("n1"), withPhase(corev1.PodRunning), withRestarts(5), withIP("1.1.1.1")),
+			pod("stable", withNode("n1"), withPhase(corev1.PodRunning), withRestarts(0), withIP("2.2.2.2")),
+		},
+		want: []string{"2.2.2.2", "1.1.1.1"},
+	}, {
+		name: "most recently ready wins ties",
+		pods: []*corev1.Pod{
+			pod("older", withNode("n1"), withPhase(corev1.PodRunning), readyAt(aTime), withIP("1.1.1.1")),
+			pod("newer", withNode("n1"), withPhase(corev1.PodRunning), readyAt(aTime.Add(time.Minute)), withIP("2.2.2.2")),
+		},
+		want: []string{"2.2.2.2", "1.1.1.1"},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakek8s.NewSimpleClientset()
+			podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+			for _, p := range tc.pods {
+				kubeClient.CoreV1().Pods(testNamespace).Create(p)
+				podsClient.Informer().GetIndexer().Add(p)
+			}
+			podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision)
+
+			got, err := podCounter.PodIPsByActive()
+			if err != nil {
+				t.Fatal("PodIPsByActive failed:", err)
+			}
+			if want := tc.want; !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+				t.Error("PodIPsByActive wrong answer (-want, +got):\n", cmp.Diff(want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
+
+func TestReadyPodIPsByActive(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+
+	pods := []*corev1.Pod{
+		pod("not-ready", withNode("n1"), withPhase(corev1.PodRunning), notReady, withIP("1.1.1.1")),
+		pod("ready", withNode("n1"), withPhase(corev1.PodRunning), readyAt(time.Now()), withIP("2.2.2.2")),
+		pod("ready-no-ip", withNode("n1"), withPhase(corev1.PodRunning), readyAt(time.Now())),
+	}
+	for _, p := range pods {
+		kubeClient.CoreV1().Pods(testNamespace).Create(p)
+		podsClient.Informer().GetIndexer().Add(p)
+	}
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision)
+
+	got, err := podCounter.ReadyPodIPsByActive()
+	if err != nil {
+		t.Fatal("ReadyPodIPsByActive failed:", err)
+	}
+	if want := []string{"2.2.2.2"}; !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+		t.Error("ReadyPodIPsByActive wrong answer (-want, +got):\n", cmp.Diff(want, got, cmpopts.EquateEmpty()))
+	}
+}
+
+func TestPodStates(t *testing.T) {
+	now := metav1.Now()
+
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		want PodStates
+	}{{
+		name: "no pods",
+		want: PodStates{},
+	}, {
+		name: "running and ready",
+		pods: []*corev1.Pod{
+			pod("running", withPhase(corev1.PodRunning), readyAt(time.Now())),
+		},
+		want: PodStates{Running: 1, Ready: 1},
+	}, {
+		name: "pending, scheduled",
+		pods: []*corev1.Pod{
+			pod("pending", withPhase(corev1.PodPending), withScheduled(true)),
+		},
+		want: PodStates{PendingScheduled: 1},
+	}, {
+		name: "pending, unschedulable",
+		pods: []*corev1.Pod{
+			pod("pending", withPhase(corev1.PodPending), withScheduled(false)),
+		},
+		want: PodStates{PendingUnschedulable: 1},
+	}, {
+		name: "container creating",
+		pods: []*corev1.Pod{
+			pod("creating", withPhase(corev1.PodPending), withWaiting("ContainerCreating")),
+		},
+		want: PodStates{PendingScheduled: 1, ContainerCreating: 1},
+	}, {
+		name: "image pull backoff",
+		pods: []*corev1.Pod{
+			pod("backoff", withPhase(corev1.PodPending), withWaiting("ImagePullBackOff")),
+		},
+		want: PodStates{PendingScheduled: 1, ImagePullBackOff: 1},
+	}, {
+		name: "crash loop backoff",
+		pods: []*corev1.Pod{
+			pod("crashlooping", withPhase(corev1.PodRunning), withWaiting("CrashLoopBackOff")),
+		},
+		want: PodStates{Running: 1, CrashLoopBackOff: 1},
+	}, {
+		name: "oom killed",
+		pods: []*corev1.Pod{
+			pod("oom", withPhase(corev1.PodRunning), withLastTerminated("OOMKilled")),
+		},
+		want: PodStates{Running: 1, OOMKilled: 1},
+	}, {
+		name: "terminating",
+		pods: []*corev1.Pod{
+			func() *corev1.Pod {
+				p := pod("terminating", withPhase(corev1.PodRunning))
+				p.DeletionTimestamp = &now
+				return p
+			}(),
+		},
+		want: PodStates{Terminating: 1},
+	}, {
+		name: "failed",
+		pods: []*corev1.Pod{
+			pod("failed", withPhase(corev1.PodFailed)),
+		},
+		want: PodStates{Failed: 1},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakek8s.NewSimpleClientset()
+			podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+			for _, p := range tc.pods {
+				kubeClient.CoreV1().Pods(testNamespace).Create(p)
+				podsClient.Informer().GetIndexer().Add(p)
+			}
+			podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision)
+
+			got, err := podCounter.PodStates()
+			if err != nil {
+				t.Fatal("PodStates failed:", err)
+			}
+			if want := tc.want; got != want {
+				t.Errorf("PodStates() = %+v, want: %+v", got, want)
+			}
+		})
+	}
+}
+
 func TestScopedPodsCounter(t *testing.T) {
 	kubeClient := fakek8s.NewSimpleClientset()
 	podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
@@ -191,6 +373,69 @@ func withIP(ip string) podOption {
 	}
 }
 
+func withNode(node string) podOption {
+	return func(p *corev1.Pod) {
+		p.Spec.NodeName = node
+	}
+}
+
+func withRestarts(n int32) podOption {
+	return func(p *corev1.Pod) {
+		p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, corev1.ContainerStatus{RestartCount: n})
+	}
+}
+
+func readyAt(t time.Time) podOption {
+	tm := metav1.NewTime(t)
+	return func(p *corev1.Pod) {
+		p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+			Type:               corev1.PodReady,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: tm,
+		})
+	}
+}
+
+func notReady(p *corev1.Pod) {
+	p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+		Type:   corev1.PodReady,
+		Status: corev1.ConditionFalse,
+	})
+}
+
+func withScheduled(scheduled bool) podOption {
+	status := corev1.ConditionTrue
+	if !scheduled {
+		status = corev1.ConditionFalse
+	}
+	return func(p *corev1.Pod) {
+		p.Status.Conditions = append(p.Status.Conditions, corev1.PodCondition{
+			Type:   corev1.PodScheduled,
+			Status: status,
+		})
+	}
+}
+
+func withWaiting(reason string) podOption {
+	return func(p *corev1.Pod) {
+		p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, corev1.ContainerStatus{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: reason},
+			},
+		})
+	}
+}
+
+func withLastTerminated(reason string) podOption {
+	return func(p *corev1.Pod) {
+		p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, corev1.ContainerStatus{
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: reason},
+			},
+		})
+	}
+}
+
 // Shortcut for a much used combo.
 func phasedPod(name string, phase corev1.PodPhase) *corev1.Pod {
 	return pod(name, withPhase(phase))
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PodStateSnapshot is the readiness/phase breakdown for a Revision's pods at a
+// point in time, as computed by PodAccessor.PodStates.
+type PodStateSnapshot = PodStates
+
+// DefaultDebounce is the debounce window Subscribe uses when called with a
+// non-positive debounce, collapsing a burst of pod transitions (e.g. during a
+// rollout) into a single callback.
+const DefaultDebounce = 100 * time.Millisecond
+
+// errNoPodInformer is returned by Subscribe when the PodAccessor wasn't built with
+// a pod informer.
+var errNoPodInformer = errors.New("PodAccessor has no pod informer configured")
+
+// WithInformer attaches the pod informer to a PodAccessor, enabling Subscribe.
+func WithInformer(podInformer coreinformers.PodInformer) PodAccessorOption {
+	return func(pa *PodAccessor) {
+		pa.podInformer = podInformer
+	}
+}
+
+// Subscribe calls fn with this Revision's PodStateSnapshot every time it changes,
+// and once up front with the current snapshot. Bursts of pod events within the
+// debounce window (DefaultDebounce if debounce is non-positive) collapse into a
+// single callback carrying the latest snapshot, so a rollout's worth of pod
+// transitions doesn't translate into a rollout's worth of callbacks. fn is never
+// called concurrently with itself, and a slow fn can never fall behind by more than
+// one snapshot: changes that arrive while fn is running simply replace the pending
+// snapshot rather than queuing. Subscribe blocks until ctx is done. The pod
+// informer event handler it adds stays registered with the informer after
+// Subscribe returns, but ignores every event from then on.
+func (pa PodAccessor) Subscribe(ctx context.Context, fn func(PodStateSnapshot), debounce time.Duration) error {
+	if pa.podInformer == nil {
+		return errNoPodInformer
+	}
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	changed := make(chan struct{}, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	notify := func(obj interface{}) {
+		select {
+		case <-stopped:
+			return
+		default:
+		}
+		p, ok := obj.(*corev1.Pod)
+		if !ok {
+			tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+			if !ok {
+				return
+			}
+			p, ok = tombstone.Obj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+		}
+		if p.Namespace != pa.namespace || !pa.selector.Matches(labels.Set(p.Labels)) {
+			return
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	pa.podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, newObj interface{}) { notify(newObj) },
+		DeleteFunc: notify,
+	})
+
+	var last PodStateSnapshot
+	haveLast := false
+	emit := func() {
+		snap, err := pa.PodStates()
+		if err != nil {
+			return
+		}
+		if haveLast && snap == last {
+			return
+		}
+		last, haveLast = snap, true
+		fn(snap)
+	}
+
+	// Deliver the current snapshot immediately rather than waiting for the first
+	// pod event, so scale-from-zero can react to pods that are already Ready.
+	emit()
+
+	timer := time.NewTimer(debounce)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	pending := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			if !pending {
+				timer.Reset(debounce)
+				pending = true
+			}
+		case <-timer.C:
+			pending = false
+			emit()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSubscribeWithoutInformer(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision)
+
+	if err := podCounter.Subscribe(context.Background(), func(PodStateSnapshot) {}, 0); err != errNoPodInformer {
+		t.Errorf("Subscribe() = %v, want %v", err, errNoPodInformer)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	factory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	podsClient := factory.Core().V1().Pods()
+
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision, WithInformer(podsClient))
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	snapshots := make(chan PodStateSnapshot, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		podCounter.Subscribe(ctx, func(s PodStateSnapshot) {
+			snapshots <- s
+		}, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case s := <-snapshots:
+		if want := (PodStateSnapshot{}); s != want {
+			t.Errorf("initial snapshot = %+v, want %+v", s, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for initial snapshot")
+	}
+
+	p := pod("running", withPhase(corev1.PodRunning), readyAt(time.Now()))
+	if _, err := kubeClient.CoreV1().Pods(testNamespace).Create(p); err != nil {
+		t.Fatal("failed to create pod:", err)
+	}
+
+	select {
+	case s := <-snapshots:
+		if want := (PodStateSnapshot{Running: 1, Ready: 1}); s != want {
+			t.Errorf("snapshot after add = %+v, want %+v", s, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for updated snapshot")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe did not return after ctx was cancelled")
+	}
+}
+
+// TestSubscribeScopedToNamespace verifies that a pod change in one namespace does
+// not trigger a subscription for a same-named Revision in a different namespace.
+func TestSubscribeScopedToNamespace(t *testing.T) {
+	const otherNamespace = "other-namespace"
+
+	kubeClient := fakek8s.NewSimpleClientset()
+	factory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	podsClient := factory.Core().V1().Pods()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	otherCounter := NewPodAccessor(podsClient.Lister(), otherNamespace, testRevision, WithInformer(podsClient))
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	otherSnapshots := make(chan PodStateSnapshot, 10)
+	done := make(chan struct{})
+	go func() {
+		otherCounter.Subscribe(ctx, func(s PodStateSnapshot) {
+			otherSnapshots <- s
+		}, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// Drain the initial snapshot for the "other-namespace" subscriber.
+	select {
+	case <-otherSnapshots:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for initial snapshot")
+	}
+
+	p := pod("running", withPhase(corev1.PodRunning), readyAt(time.Now()))
+	if _, err := kubeClient.CoreV1().Pods(testNamespace).Create(p); err != nil {
+		t.Fatal("failed to create pod:", err)
+	}
+
+	select {
+	case s := <-otherSnapshots:
+		t.Errorf("got unexpected snapshot %+v for a pod created in a different namespace", s)
+	case <-time.After(200 * time.Millisecond):
+		// Expected: no cross-namespace notification.
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe did not return after ctx was cancelled")
+	}
+}
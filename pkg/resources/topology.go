@@ -0,0 +1,242 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"errors"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// zoneLabel and hostnameLabel are the well-known node labels used to determine
+// where a pod is physically running.
+const (
+	zoneLabel     = "topology.kubernetes.io/zone"
+	hostnameLabel = "kubernetes.io/hostname"
+)
+
+// errNoTopologyIndex is returned by the topology-aware PodAccessor methods when the
+// accessor wasn't built with a PodTopologyIndex.
+var errNoTopologyIndex = errors.New("PodAccessor has no PodTopologyIndex configured")
+
+// podLocation is the slice of a node's topology labels that matters for routing:
+// its zone and its hostname (conventionally, but not necessarily, the node name).
+type podLocation struct {
+	zone     string
+	hostname string
+}
+
+// PodTopologyIndex maintains a cache of which zone and hostname each known pod's
+// node carries. It's built once per process (it isn't scoped to a Revision) and
+// shared across every PodAccessor, so that topology-scoped queries don't need to
+// hit the node lister on the hot path. It stays current by watching the pod
+// informer directly rather than being refreshed on each lookup.
+type PodTopologyIndex struct {
+	nodeLister corev1listers.NodeLister
+
+	mu          sync.RWMutex
+	podLocation map[types.NamespacedName]podLocation
+}
+
+// NewPodTopologyIndex creates a PodTopologyIndex backed by nodeLister and registers
+// it with podInformer to keep its pod-to-location cache up to date as pods are
+// added, updated and removed.
+func NewPodTopologyIndex(nodeLister corev1listers.NodeLister, podInformer coreinformers.PodInformer) *PodTopologyIndex {
+	idx := &PodTopologyIndex{
+		nodeLister:  nodeLister,
+		podLocation: make(map[types.NamespacedName]podLocation),
+	}
+
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: idx.update,
+		UpdateFunc: func(_, newObj interface{}) {
+			idx.update(newObj)
+		},
+		DeleteFunc: idx.remove,
+	})
+
+	return idx
+}
+
+func (idx *PodTopologyIndex) update(obj interface{}) {
+	p, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	key := types.NamespacedName{Namespace: p.Namespace, Name: p.Name}
+	loc := idx.locationForNode(p.Spec.NodeName)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if loc == (podLocation{}) {
+		delete(idx.podLocation, key)
+		return
+	}
+	idx.podLocation[key] = loc
+}
+
+func (idx *PodTopologyIndex) remove(obj interface{}) {
+	p, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		p, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.podLocation, types.NamespacedName{Namespace: p.Namespace, Name: p.Name})
+}
+
+func (idx *PodTopologyIndex) locationForNode(nodeName string) podLocation {
+	if nodeName == "" {
+		return podLocation{}
+	}
+	node, err := idx.nodeLister.Get(nodeName)
+	if err != nil {
+		return podLocation{}
+	}
+	return podLocation{
+		zone:     node.Labels[zoneLabel],
+		hostname: node.Labels[hostnameLabel],
+	}
+}
+
+// zoneFor returns the cached zone for the given pod, and whether it's known.
+func (idx *PodTopologyIndex) zoneFor(p *corev1.Pod) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	loc, ok := idx.podLocation[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}]
+	return loc.zone, ok && loc.zone != ""
+}
+
+// hostnameFor returns the cached kubernetes.io/hostname label for the node the
+// given pod is running on, and whether it's known.
+func (idx *PodTopologyIndex) hostnameFor(p *corev1.Pod) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	loc, ok := idx.podLocation[types.NamespacedName{Namespace: p.Namespace, Name: p.Name}]
+	return loc.hostname, ok && loc.hostname != ""
+}
+
+// PodAccessorOption customizes a PodAccessor at construction time.
+type PodAccessorOption func(*PodAccessor)
+
+// WithTopologyIndex attaches a PodTopologyIndex to a PodAccessor, enabling
+// PodIPsInTopology, PodIPsByTopology and PodIPsOnNodes.
+func WithTopologyIndex(idx *PodTopologyIndex) PodAccessorOption {
+	return func(pa *PodAccessor) {
+		pa.topology = idx
+	}
+}
+
+// PodIPsInTopology returns the IP addresses of the pods for this Revision that are
+// running on a node in the given zone. The activator uses this to prefer same-zone
+// pods and cut cross-AZ traffic cost, falling back to PodIPsByActive when this
+// returns no results.
+func (pa PodAccessor) PodIPsInTopology(zone string) ([]string, error) {
+	if pa.topology == nil {
+		return nil, errNoTopologyIndex
+	}
+
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(pods))
+	for _, p := range pods {
+		if p.Status.PodIP == "" {
+			continue
+		}
+		if z, ok := pa.topology.zoneFor(p); ok && z == zone {
+			ips = append(ips, p.Status.PodIP)
+		}
+	}
+	return ips, nil
+}
+
+// PodIPsByTopology returns the IP addresses of all pods for this Revision which have
+// an IP, grouped by zone. Pods whose zone isn't yet known (e.g. not yet scheduled)
+// are omitted.
+func (pa PodAccessor) PodIPsByTopology() (map[string][]string, error) {
+	if pa.topology == nil {
+		return nil, errNoTopologyIndex
+	}
+
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	byZone := make(map[string][]string)
+	for _, p := range pods {
+		if p.Status.PodIP == "" {
+			continue
+		}
+		if z, ok := pa.topology.zoneFor(p); ok {
+			byZone[z] = append(byZone[z], p.Status.PodIP)
+		}
+	}
+	return byZone, nil
+}
+
+// PodIPsOnNodes returns the IP addresses of the pods for this Revision that are
+// running on one of the given nodes (matched by the node's kubernetes.io/hostname
+// label). This lets a caller restrict routing to a specific set of nodes, e.g. to
+// prefer node-local pods.
+func (pa PodAccessor) PodIPsOnNodes(nodeNames ...string) ([]string, error) {
+	if pa.topology == nil {
+		return nil, errNoTopologyIndex
+	}
+
+	want := make(map[string]struct{}, len(nodeNames))
+	for _, n := range nodeNames {
+		want[n] = struct{}{}
+	}
+
+	pods, err := pa.podsLister.List(pa.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(pods))
+	for _, p := range pods {
+		if p.Status.PodIP == "" {
+			continue
+		}
+		host, ok := pa.topology.hostnameFor(p)
+		if !ok {
+			continue
+		}
+		if _, match := want[host]; match {
+			ips = append(ips, p.Status.PodIP)
+		}
+	}
+	return ips, nil
+}
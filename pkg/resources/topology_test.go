@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+)
+
+func node(name, zone string) *corev1.Node {
+	return nodeWithHostname(name, zone, name)
+}
+
+func nodeWithHostname(name, zone, hostname string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				zoneLabel:     zone,
+				hostnameLabel: hostname,
+			},
+		},
+	}
+}
+
+func TestPodIPsInTopology(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	factory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	nodesClient := factory.Core().V1().Nodes()
+	podsClient := factory.Core().V1().Pods()
+
+	for _, n := range []*corev1.Node{node("node-a", "zone-a"), node("node-b", "zone-b")} {
+		kubeClient.CoreV1().Nodes().Create(n)
+		nodesClient.Informer().GetIndexer().Add(n)
+	}
+
+	idx := NewPodTopologyIndex(nodesClient.Lister(), podsClient)
+
+	pods := []*corev1.Pod{
+		pod("a1", withNode("node-a"), withIP("1.1.1.1")),
+		pod("a2", withNode("node-a"), withIP("1.1.1.2")),
+		pod("b1", withNode("node-b"), withIP("2.2.2.1")),
+		pod("unscheduled", withIP("3.3.3.3")),
+	}
+	for _, p := range pods {
+		kubeClient.CoreV1().Pods(testNamespace).Create(p)
+		podsClient.Informer().GetIndexer().Add(p)
+		idx.update(p)
+	}
+
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision, WithTopologyIndex(idx))
+
+	got, err := podCounter.PodIPsInTopology("zone-a")
+	if err != nil {
+		t.Fatal("PodIPsInTopology failed:", err)
+	}
+	if want := []string{"1.1.1.1", "1.1.1.2"}; !cmp.Equal(got, want, cmpopts.EquateEmpty(), cmpopts.SortSlices(func(a, b string) bool { return a < b })) {
+		t.Error("PodIPsInTopology wrong answer (-want, +got):\n", cmp.Diff(want, got, cmpopts.EquateEmpty()))
+	}
+
+	byZone, err := podCounter.PodIPsByTopology()
+	if err != nil {
+		t.Fatal("PodIPsByTopology failed:", err)
+	}
+	want := map[string][]string{
+		"zone-a": {"1.1.1.1", "1.1.1.2"},
+		"zone-b": {"2.2.2.1"},
+	}
+	if !cmp.Equal(byZone, want, cmpopts.EquateEmpty(), cmpopts.SortSlices(func(a, b string) bool { return a < b })) {
+		t.Error("PodIPsByTopology wrong answer (-want, +got):\n", cmp.Diff(want, byZone, cmpopts.EquateEmpty()))
+	}
+}
+
+func TestPodIPsOnNodes(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	factory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	nodesClient := factory.Core().V1().Nodes()
+	podsClient := factory.Core().V1().Pods()
+
+	nodes := []*corev1.Node{
+		nodeWithHostname("node-a", "zone-a", "host-a"),
+		nodeWithHostname("node-b", "zone-a", "host-b"),
+	}
+	for _, n := range nodes {
+		kubeClient.CoreV1().Nodes().Create(n)
+		nodesClient.Informer().GetIndexer().Add(n)
+	}
+
+	idx := NewPodTopologyIndex(nodesClient.Lister(), podsClient)
+
+	pods := []*corev1.Pod{
+		pod("a1", withNode("node-a"), withIP("1.1.1.1")),
+		pod("b1", withNode("node-b"), withIP("2.2.2.1")),
+		pod("unscheduled", withIP("3.3.3.3")),
+	}
+	for _, p := range pods {
+		kubeClient.CoreV1().Pods(testNamespace).Create(p)
+		podsClient.Informer().GetIndexer().Add(p)
+		idx.update(p)
+	}
+
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision, WithTopologyIndex(idx))
+
+	got, err := podCounter.PodIPsOnNodes("host-a")
+	if err != nil {
+		t.Fatal("PodIPsOnNodes failed:", err)
+	}
+	if want := []string{"1.1.1.1"}; !cmp.Equal(got, want, cmpopts.EquateEmpty()) {
+		t.Error("PodIPsOnNodes wrong answer (-want, +got):\n", cmp.Diff(want, got, cmpopts.EquateEmpty()))
+	}
+}
+
+func TestPodIPsInTopologyWithoutIndex(t *testing.T) {
+	kubeClient := fakek8s.NewSimpleClientset()
+	podsClient := kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Pods()
+	podCounter := NewPodAccessor(podsClient.Lister(), testNamespace, testRevision)
+
+	if _, err := podCounter.PodIPsInTopology("zone-a"); err == nil {
+		t.Error("PodIPsInTopology() = nil error, want an error when no topology index is configured")
+	}
+	if _, err := podCounter.PodIPsByTopology(); err == nil {
+		t.Error("PodIPsByTopology() = nil error, want an error when no topology index is configured")
+	}
+	if _, err := podCounter.PodIPsOnNodes("host-a"); err == nil {
+		t.Error("PodIPsOnNodes() = nil error, want an error when no topology index is configured")
+	}
+}